@@ -0,0 +1,84 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package discovery provides a typed representation of a Google API Discovery Document,
+// the JSON format served at https://www.googleapis.com/discovery/v1/apis/{api}/{version}/rest.
+package discovery
+
+import "encoding/json"
+
+// Document is the root of a Discovery Document.
+type Document struct {
+	Name      string               `json:"name,omitempty"`
+	Version   string               `json:"version,omitempty"`
+	Title     string               `json:"title,omitempty"`
+	RootURL   string               `json:"rootUrl,omitempty"`
+	BasePath  string               `json:"basePath,omitempty"`
+	Schemas   map[string]*Schema   `json:"schemas,omitempty"`
+	Resources map[string]*Resource `json:"resources,omitempty"`
+	Methods   map[string]*Method   `json:"methods,omitempty"`
+}
+
+// Schema describes a named type in the "schemas" section of a Discovery Document.
+type Schema struct {
+	Id                   string             `json:"id,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+}
+
+// Resource is a named grouping of methods and nested resources, such as "users" or
+// "users.messages".
+type Resource struct {
+	Methods   map[string]*Method   `json:"methods,omitempty"`
+	Resources map[string]*Resource `json:"resources,omitempty"`
+}
+
+// Method describes a single RPC exposed by a resource.
+type Method struct {
+	Id          string                `json:"id,omitempty"`
+	Path        string                `json:"path,omitempty"`
+	HTTPMethod  string                `json:"httpMethod,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Parameters  map[string]*Parameter `json:"parameters,omitempty"`
+	Request     *SchemaRef            `json:"request,omitempty"`
+	Response    *SchemaRef            `json:"response,omitempty"`
+}
+
+// Parameter describes a single path, query, or header parameter of a Method.
+type Parameter struct {
+	Type        string `json:"type,omitempty"`
+	Format      string `json:"format,omitempty"`
+	Description string `json:"description,omitempty"`
+	Location    string `json:"location,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// SchemaRef points to a named Schema by id, as used for a Method's request and response.
+type SchemaRef struct {
+	Ref string `json:"$ref,omitempty"`
+}
+
+// ParseDocument unmarshals a Discovery Document from its JSON representation.
+func ParseDocument(b []byte) (*Document, error) {
+	var d Document
+	if err := json.Unmarshal(b, &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
@@ -0,0 +1,306 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	surface "github.com/googleapis/gnostic/plugins/gnostic-go-generator/surface"
+)
+
+// ClientGenerator renders a surface.Model as an idiomatic Go HTTP client package.
+type ClientGenerator struct {
+	model *surface.Model
+}
+
+// NewClientGenerator creates a ClientGenerator for model.
+func NewClientGenerator(model *surface.Model) *ClientGenerator {
+	return &ClientGenerator{model: model}
+}
+
+// Generate renders the client package source, with the given package name, for g's model.
+func (g *ClientGenerator) Generate(packageName string) ([]byte, error) {
+	resources, resourceNames := g.groupMethodsByResource()
+	usesMedia := g.usesMedia()
+	usesResumableUpload := g.usesMediaUpload()
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by gnostic-go-client. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package %s\n\n", packageName)
+	imports := []string{"bytes", "context", "encoding/json", "fmt", "net/http", "net/url", "strings"}
+	if usesMedia {
+		imports = append(imports, "io")
+	}
+	if usesResumableUpload {
+		imports = append(imports, "strconv")
+	}
+	sort.Strings(imports)
+	fmt.Fprint(&b, "import (\n")
+	for _, imp := range imports {
+		fmt.Fprintf(&b, "\t%q\n", imp)
+	}
+	fmt.Fprint(&b, ")\n\n")
+	g.generatePathRuntime(&b)
+	if usesMedia {
+		g.generateMediaRuntime(&b)
+	}
+
+	fmt.Fprintf(&b, "// Service is the entry point for the %s API.\n", g.model.Name)
+	fmt.Fprint(&b, "type Service struct {\n\tclient   *http.Client\n\tBasePath string\n\n")
+	for _, name := range resourceNames {
+		fmt.Fprintf(&b, "\t%s *%sService\n", name, name)
+	}
+	fmt.Fprint(&b, "}\n\n")
+
+	fmt.Fprint(&b, "// New creates a Service that issues requests with client against basePath.\n")
+	fmt.Fprint(&b, "func New(client *http.Client, basePath string) *Service {\n")
+	fmt.Fprint(&b, "\ts := &Service{client: client, BasePath: basePath}\n")
+	for _, name := range resourceNames {
+		fmt.Fprintf(&b, "\ts.%s = &%sService{s: s}\n", name, name)
+	}
+	fmt.Fprint(&b, "\treturn s\n}\n\n")
+
+	for _, name := range resourceNames {
+		fmt.Fprintf(&b, "// %sService groups the methods of the %s resource.\n", name, name)
+		fmt.Fprintf(&b, "type %sService struct {\n\ts *Service\n}\n\n", name)
+		for _, m := range resources[name] {
+			g.generateCall(&b, name, m)
+		}
+	}
+
+	return b.Bytes(), nil
+}
+
+// groupMethodsByResource buckets the model's methods by the resource derived from each
+// method's path, e.g. "/users/{id}/messages" -> "UsersMessages".
+func (g *ClientGenerator) groupMethodsByResource() (map[string][]*surface.Method, []string) {
+	resources := map[string][]*surface.Method{}
+	var resourceNames []string
+	for _, m := range g.model.Methods {
+		resource := resourceForPath(m.Path)
+		if _, ok := resources[resource]; !ok {
+			resourceNames = append(resourceNames, resource)
+		}
+		resources[resource] = append(resources[resource], m)
+	}
+	sort.Strings(resourceNames)
+	return resources, resourceNames
+}
+
+// resourceForPath derives the name of the sub-service that owns a method from the static
+// segments of its path.
+func resourceForPath(path string) string {
+	var parts []string
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "" || strings.HasPrefix(segment, "{") {
+			continue
+		}
+		parts = append(parts, strings.Title(segment))
+	}
+	if len(parts) == 0 {
+		return "Root"
+	}
+	return strings.Join(parts, "")
+}
+
+// generateCall renders the *Call builder type for a single method, including its
+// chainable parameter setters and its Do method.
+func (g *ClientGenerator) generateCall(b *bytes.Buffer, resource string, m *surface.Method) {
+	callType := m.Name + "Call"
+	responseType := g.responseTypeOf(m)
+	params := g.parametersOf(m)
+
+	fmt.Fprintf(b, "// %s starts a call to %s %s.\n", m.Name, m.Method, m.Path)
+	fmt.Fprintf(b, "func (r *%sService) %s() *%s {\n", resource, m.Name, callType)
+	fmt.Fprintf(b, "\treturn &%s{s: r.s, pathParams: map[string]string{}, queryParams: url.Values{}, headerParams: http.Header{}, formParams: url.Values{}}\n}\n\n", callType)
+
+	fmt.Fprintf(b, "// %s builds and sends a %s request to %s.\n", callType, m.Method, m.Path)
+	fmt.Fprintf(b, "type %s struct {\n", callType)
+	fmt.Fprint(b, "\ts            *Service\n\tpathParams   map[string]string\n\tqueryParams  url.Values\n\theaderParams http.Header\n\tformParams   url.Values\n\tbody         interface{}\n")
+	if m.SupportsMediaUpload {
+		fmt.Fprint(b, "\tmedia        io.Reader\n\tmediaOpts    mediaUploadOptions\n")
+	}
+	fmt.Fprint(b, "}\n\n")
+
+	for _, param := range params {
+		setter := strings.Title(param.Name)
+		switch param.Position {
+		case surface.Position_PATH:
+			fmt.Fprintf(b, "// %s sets the %s path parameter.\n", setter, param.Name)
+			fmt.Fprintf(b, "func (c *%s) %s(v %s) *%s {\n", callType, setter, param.Type, callType)
+			fmt.Fprintf(b, "\tc.pathParams[%q] = fmt.Sprintf(\"%%v\", v)\n\treturn c\n}\n\n", param.Name)
+		case surface.Position_QUERY:
+			fmt.Fprintf(b, "// %s sets the %s query parameter.\n", setter, param.Name)
+			fmt.Fprintf(b, "func (c *%s) %s(v %s) *%s {\n", callType, setter, param.Type, callType)
+			fmt.Fprintf(b, "\tc.queryParams.Set(%q, fmt.Sprintf(\"%%v\", v))\n\treturn c\n}\n\n", param.Name)
+		case surface.Position_HEADER:
+			fmt.Fprintf(b, "// %s sets the %s header.\n", setter, param.Name)
+			fmt.Fprintf(b, "func (c *%s) %s(v %s) *%s {\n", callType, setter, param.Type, callType)
+			fmt.Fprintf(b, "\tc.headerParams.Set(%q, fmt.Sprintf(\"%%v\", v))\n\treturn c\n}\n\n", param.Name)
+		case surface.Position_FORMDATA:
+			fmt.Fprintf(b, "// %s sets the %s form field.\n", setter, param.Name)
+			fmt.Fprintf(b, "func (c *%s) %s(v %s) *%s {\n", callType, setter, param.Type, callType)
+			fmt.Fprintf(b, "\tc.formParams.Set(%q, fmt.Sprintf(\"%%v\", v))\n\treturn c\n}\n\n", param.Name)
+		case surface.Position_BODY:
+			fmt.Fprintf(b, "// %s sets the request body.\n", setter)
+			fmt.Fprintf(b, "func (c *%s) %s(v %s) *%s {\n\tc.body = v\n\treturn c\n}\n\n", callType, setter, param.Type, callType)
+		}
+	}
+
+	if m.SupportsMediaUpload {
+		fmt.Fprint(b, "// Media attaches r as the body to upload; Do sends it as a resumable, chunked PUT.\n")
+		fmt.Fprintf(b, "func (c *%s) Media(r io.Reader, opts ...MediaOption) *%s {\n", callType, callType)
+		fmt.Fprint(b, "\tc.media = r\n\tc.mediaOpts = defaultMediaUploadOptions()\n\tfor _, opt := range opts {\n\t\topt(&c.mediaOpts)\n\t}\n\treturn c\n}\n\n")
+	}
+
+	if m.SupportsMediaDownload {
+		fmt.Fprint(b, "// Download sends the request and returns the raw response body for streaming.\n")
+		fmt.Fprintf(b, "func (c *%s) Download(ctx context.Context) (io.ReadCloser, error) {\n", callType)
+		fmt.Fprintf(b, "\treq, err := http.NewRequestWithContext(ctx, %q, c.s.BasePath+c.resolvedPath(), nil)\n\tif err != nil {\n\t\treturn nil, err\n\t}\n", m.Method)
+		fmt.Fprint(b, "\tc.applyHeaderParams(req)\n")
+		fmt.Fprint(b, "\tresp, err := c.s.client.Do(req)\n\tif err != nil {\n\t\treturn nil, err\n\t}\n")
+		fmt.Fprintf(b, "\tif resp.StatusCode < 200 || resp.StatusCode >= 300 {\n\t\tresp.Body.Close()\n\t\treturn nil, fmt.Errorf(\"%s: unexpected status %%d\", resp.StatusCode)\n\t}\n", callType)
+		fmt.Fprint(b, "\treturn resp.Body, nil\n}\n\n")
+	}
+
+	fmt.Fprint(b, "// applyHeaderParams sets c's header parameters on req.\n")
+	fmt.Fprintf(b, "func (c *%s) applyHeaderParams(req *http.Request) {\n", callType)
+	fmt.Fprint(b, "\tfor name := range c.headerParams {\n\t\treq.Header.Set(name, c.headerParams.Get(name))\n\t}\n}\n\n")
+
+	fmt.Fprint(b, "// resolvedPath substitutes c's path parameters and appends its query parameters.\n")
+	fmt.Fprintf(b, "func (c *%s) resolvedPath() string {\n", callType)
+	fmt.Fprintf(b, "\tpath := substitutePathParams(%q, c.pathParams)\n", m.Path)
+	fmt.Fprint(b, "\tif q := c.queryParams.Encode(); q != \"\" {\n\t\tpath += \"?\" + q\n\t}\n\treturn path\n}\n\n")
+
+	fmt.Fprint(b, "// Do sends the request and decodes the response.\n")
+	fmt.Fprintf(b, "func (c *%s) Do(ctx context.Context) (*%s, error) {\n", callType, responseType)
+	if m.SupportsMediaUpload {
+		fmt.Fprintf(b, "\tif c.media != nil {\n\t\treturn c.doResumableUpload(ctx)\n\t}\n\n")
+	}
+	fmt.Fprint(b, "\tvar body *bytes.Reader\n\tcontentType := \"\"\n")
+	fmt.Fprint(b, "\tswitch {\n\tcase len(c.formParams) > 0:\n\t\tbody = bytes.NewReader([]byte(c.formParams.Encode()))\n\t\tcontentType = \"application/x-www-form-urlencoded\"\n")
+	fmt.Fprint(b, "\tcase c.body != nil:\n\t\tdata, err := json.Marshal(c.body)\n\t\tif err != nil {\n\t\t\treturn nil, err\n\t\t}\n\t\tbody = bytes.NewReader(data)\n\t\tcontentType = \"application/json\"\n")
+	fmt.Fprint(b, "\tdefault:\n\t\tbody = bytes.NewReader(nil)\n\t}\n\n")
+	fmt.Fprintf(b, "\treq, err := http.NewRequestWithContext(ctx, %q, c.s.BasePath+c.resolvedPath(), body)\n\tif err != nil {\n\t\treturn nil, err\n\t}\n", m.Method)
+	fmt.Fprint(b, "\tif contentType != \"\" {\n\t\treq.Header.Set(\"Content-Type\", contentType)\n\t}\n\tc.applyHeaderParams(req)\n\n")
+	fmt.Fprint(b, "\tresp, err := c.s.client.Do(req)\n\tif err != nil {\n\t\treturn nil, err\n\t}\n\tdefer resp.Body.Close()\n\n")
+	fmt.Fprintf(b, "\tif resp.StatusCode < 200 || resp.StatusCode >= 300 {\n\t\treturn nil, fmt.Errorf(\"%s: unexpected status %%d\", resp.StatusCode)\n\t}\n\n", callType)
+	fmt.Fprintf(b, "\tvar out %s\n\tif err := json.NewDecoder(resp.Body).Decode(&out); err != nil {\n\t\treturn nil, err\n\t}\n\treturn &out, nil\n}\n\n", responseType)
+
+	if m.SupportsMediaUpload {
+		fmt.Fprint(b, "// doResumableUpload sends c.media as a series of Content-Range chunks, polling the\n")
+		fmt.Fprint(b, "// Upload-Status header after each one until the server reports the upload is complete.\n")
+		fmt.Fprintf(b, "func (c *%s) doResumableUpload(ctx context.Context) (*%s, error) {\n", callType, responseType)
+		fmt.Fprintf(b, "\tuploadURL := c.s.BasePath + substitutePathParams(%q, c.pathParams)\n", mediaUploadPath(m))
+		fmt.Fprint(b, "\tchunk := make([]byte, c.mediaOpts.chunkSize)\n\tvar offset int64\n\tfor {\n")
+		fmt.Fprint(b, "\t\tn, readErr := io.ReadFull(c.media, chunk)\n\t\tif n == 0 && readErr == io.EOF {\n\t\t\tbreak\n\t\t}\n")
+		fmt.Fprint(b, "\t\treq, err := http.NewRequestWithContext(ctx, http.MethodPut, uploadURL, bytes.NewReader(chunk[:n]))\n\t\tif err != nil {\n\t\t\treturn nil, err\n\t\t}\n")
+		fmt.Fprint(b, "\t\treq.Header.Set(\"Content-Range\", fmt.Sprintf(\"bytes %s-%s/*\", strconv.FormatInt(offset, 10), strconv.FormatInt(offset+int64(n)-1, 10)))\n")
+		fmt.Fprint(b, "\t\tc.applyHeaderParams(req)\n")
+		fmt.Fprint(b, "\t\tresp, err := c.s.client.Do(req)\n\t\tif err != nil {\n\t\t\treturn nil, err\n\t\t}\n")
+		fmt.Fprint(b, "\t\tif resp.StatusCode != http.StatusAccepted && resp.Header.Get(\"Upload-Status\") != \"final\" {\n")
+		fmt.Fprint(b, "\t\t\tresp.Body.Close()\n\t\t\toffset += int64(n)\n\t\t\tif readErr == io.EOF || readErr == io.ErrUnexpectedEOF {\n\t\t\t\tbreak\n\t\t\t}\n\t\t\tcontinue\n\t\t}\n")
+		fmt.Fprintf(b, "\t\tvar out %s\n\t\terr = json.NewDecoder(resp.Body).Decode(&out)\n\t\tresp.Body.Close()\n\t\tif err != nil {\n\t\t\treturn nil, err\n\t\t}\n\t\treturn &out, nil\n", responseType)
+		fmt.Fprint(b, "\t}\n\treturn nil, fmt.Errorf(\"upload did not complete\")\n}\n\n")
+	}
+}
+
+// mediaUploadPath returns the path the generated client PUTs resumable upload chunks to.
+func mediaUploadPath(m *surface.Method) string {
+	if m.MediaUploadPath != "" {
+		return m.MediaUploadPath
+	}
+	return m.Path + "/upload"
+}
+
+// usesMedia reports whether any method in g's model supports media upload or download.
+func (g *ClientGenerator) usesMedia() bool {
+	for _, m := range g.model.Methods {
+		if m.SupportsMediaUpload || m.SupportsMediaDownload {
+			return true
+		}
+	}
+	return false
+}
+
+// usesMediaUpload reports whether any method in g's model supports resumable media upload.
+func (g *ClientGenerator) usesMediaUpload() bool {
+	for _, m := range g.model.Methods {
+		if m.SupportsMediaUpload {
+			return true
+		}
+	}
+	return false
+}
+
+// generatePathRuntime emits substitutePathParams, shared by every *Call's resolvedPath and,
+// for methods that support it, doResumableUpload.
+func (g *ClientGenerator) generatePathRuntime(b *bytes.Buffer) {
+	fmt.Fprint(b, "// substitutePathParams replaces each {name} placeholder in path with its value from\n")
+	fmt.Fprint(b, "// params, URL-escaped.\n")
+	fmt.Fprint(b, "func substitutePathParams(path string, params map[string]string) string {\n")
+	fmt.Fprint(b, "\tfor name, value := range params {\n")
+	fmt.Fprint(b, "\t\tpath = strings.Replace(path, \"{\"+name+\"}\", url.PathEscape(value), 1)\n\t}\n\treturn path\n}\n\n")
+}
+
+// generateMediaRuntime emits the MediaOption type and its default used by every *Call that
+// supports media upload.
+func (g *ClientGenerator) generateMediaRuntime(b *bytes.Buffer) {
+	fmt.Fprint(b, "// mediaUploadOptions configures a resumable media upload.\n")
+	fmt.Fprint(b, "type mediaUploadOptions struct {\n\tchunkSize int64\n}\n\n")
+	fmt.Fprint(b, "func defaultMediaUploadOptions() mediaUploadOptions {\n\treturn mediaUploadOptions{chunkSize: 8 << 20}\n}\n\n")
+	fmt.Fprint(b, "// MediaOption configures a resumable media upload started by a *Call's Media method.\n")
+	fmt.Fprint(b, "type MediaOption func(*mediaUploadOptions)\n\n")
+	fmt.Fprint(b, "// ChunkSize sets the size, in bytes, of each chunk sent during a resumable upload.\n")
+	fmt.Fprint(b, "func ChunkSize(n int64) MediaOption {\n\treturn func(o *mediaUploadOptions) { o.chunkSize = n }\n}\n\n")
+}
+
+// parametersOf returns the non-body-holding Fields of the Type that models m's parameters.
+func (g *ClientGenerator) parametersOf(m *surface.Method) []*surface.Field {
+	for _, t := range g.model.Types {
+		if t.Name == m.ParametersTypeName {
+			return t.Fields
+		}
+	}
+	return nil
+}
+
+// responseTypeOf returns the name of the Go type that models m's successful response.
+func (g *ClientGenerator) responseTypeOf(m *surface.Method) string {
+	for _, t := range g.model.Types {
+		if t.Name == m.ResponsesTypeName && len(t.Fields) > 0 {
+			return t.Fields[0].ValueType
+		}
+	}
+	return "interface{}"
+}
+
+// sanitizePackageName lowercases and strips non-identifier characters from an API title to
+// produce a usable Go package name, e.g. "Example API" -> "exampleapi".
+func sanitizePackageName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() == 0 {
+		return "client"
+	}
+	return b.String()
+}
@@ -0,0 +1,60 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	surface "github.com/googleapis/gnostic/plugins/gnostic-go-generator/surface"
+)
+
+// TestResumableUploadDecodesBeforeClosingBody guards against the response body being closed
+// before the final chunk's JSON response is decoded from it: a prior version of
+// doResumableUpload's template closed the body unconditionally right after every chunk PUT,
+// which made the success path's decode always fail.
+func TestResumableUploadDecodesBeforeClosingBody(t *testing.T) {
+	model := &surface.Model{
+		Name: "Test",
+		Methods: []*surface.Method{
+			{
+				Name:                "InsertMedia",
+				Path:                "/files",
+				Method:              "POST",
+				SupportsMediaUpload: true,
+			},
+		},
+	}
+	code, err := NewClientGenerator(model).Generate("test")
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	src := string(code)
+
+	fnStart := strings.Index(src, "func (c *InsertMediaCall) doResumableUpload(")
+	if fnStart < 0 {
+		t.Fatalf("generated source has no doResumableUpload method:\n%s", src)
+	}
+	fn := src[fnStart:]
+
+	decodeIdx := strings.Index(fn, "json.NewDecoder(resp.Body).Decode(&out)")
+	if decodeIdx < 0 {
+		t.Fatalf("doResumableUpload never decodes the response body:\n%s", fn)
+	}
+	closeIdx := strings.LastIndex(fn[:decodeIdx], "resp.Body.Close()")
+	if closeIdx >= 0 {
+		t.Errorf("doResumableUpload closes resp.Body before decoding it:\n%s", fn[:decodeIdx])
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// gnostic-go-client is a gnostic plugin that reads a surface.Model built from an OpenAPI
+// document and emits an idiomatic, hand-style Go HTTP client package: a Service per API,
+// one sub-service per resource, and a chainable *Call builder per method.
+package main
+
+import (
+	"errors"
+
+	plugins "github.com/googleapis/gnostic/plugins"
+	surface "github.com/googleapis/gnostic/plugins/gnostic-go-generator/surface"
+)
+
+func main() {
+	env, err := plugins.NewEnvironment()
+	env.RespondAndExitIfError(err)
+
+	model, err := modelFromRequest(env.Request)
+	env.RespondAndExitIfError(err)
+
+	code, err := NewClientGenerator(model).Generate(sanitizePackageName(model.Name))
+	env.RespondAndExitIfError(err)
+
+	env.Response.Files = append(env.Response.Files, &plugins.File{
+		Name: "client.go",
+		Data: code,
+	})
+	env.RespondAndExit()
+}
+
+// modelFromRequest builds a surface.Model from whichever source document the plugin
+// request carries.
+func modelFromRequest(request *plugins.Request) (*surface.Model, error) {
+	if document := request.GetSourceOpenAPI2(); document != nil {
+		return surface.NewModelFromOpenAPI2(document)
+	}
+	if document := request.GetSourceDiscovery(); document != nil {
+		return surface.NewModelFromDiscovery(document)
+	}
+	if document := request.GetSourceOpenAPI3(); document != nil {
+		return surface.NewModelFromOpenAPI3(document)
+	}
+	return nil, errors.New("gnostic-go-client: no supported source document (OpenAPI v2, OpenAPI v3, or Discovery) in request")
+}
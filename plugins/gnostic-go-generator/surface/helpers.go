@@ -0,0 +1,257 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gnostic_surface_v1
+
+import (
+	"fmt"
+	"strings"
+
+	openapiv3 "github.com/googleapis/gnostic/OpenAPIv3"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// typeForRef converts a JSON "$ref" such as "#/definitions/Pet" into the name of the
+// generated type it points to, "Pet".
+func typeForRef(ref string) (typeName string) {
+	parts := strings.Split(ref, "/")
+	return parts[len(parts)-1]
+}
+
+// sanitizeOperationName removes characters from an operationId that would be invalid in a
+// generated Go identifier.
+func sanitizeOperationName(name string) string {
+	name = strings.Replace(name, ".", "_", -1)
+	return name
+}
+
+// generateOperationName builds an operation name from an HTTP method and path for use when
+// the source document does not provide an explicit operationId.
+func generateOperationName(method string, path string) (name string) {
+	name = strings.Title(strings.ToLower(method))
+	path = strings.Replace(path, "/", "_", -1)
+	path = strings.Replace(path, "{", "", -1)
+	path = strings.Replace(path, "}", "", -1)
+	return name + path
+}
+
+// nameForDottedPath CamelCases a dotted nested-resource path, such as the one
+// buildMethodsFromResource builds up while walking a Discovery Document's resources, e.g.
+// "users.messages.list" -> "UsersMessagesList".
+func nameForDottedPath(dotted string) string {
+	var b strings.Builder
+	for _, segment := range strings.Split(dotted, ".") {
+		b.WriteString(strings.Title(segment))
+	}
+	return b.String()
+}
+
+// stringForDefault renders a schema's "default" value (an untyped JSON literal) as the
+// string a code generator can splice into a Go default-value expression. A nil default
+// renders as the empty string so callers can treat it as "not present".
+func stringForDefault(def interface{}) string {
+	if def == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", def)
+}
+
+// stringsForEnum renders a schema's "enum" values (untyped JSON literals) as strings for
+// use as generated enum constants.
+func stringsForEnum(enum []interface{}) []string {
+	if len(enum) == 0 {
+		return nil
+	}
+	values := make([]string, 0, len(enum))
+	for _, v := range enum {
+		values = append(values, fmt.Sprintf("%v", v))
+	}
+	return values
+}
+
+// validationForParameter collects a parameter's numeric, string-length, and pattern
+// constraints into a Validation, or returns nil if none were specified.
+func validationForParameter(pattern string, minimum, maximum float64, minLength, maxLength int64) *Validation {
+	if pattern == "" && minimum == 0 && maximum == 0 && minLength == 0 && maxLength == 0 {
+		return nil
+	}
+	return &Validation{
+		Pattern:   pattern,
+		Minimum:   minimum,
+		Maximum:   maximum,
+		MinLength: minLength,
+		MaxLength: maxLength,
+	}
+}
+
+// pointerForDefinition returns the JSON-pointer path of a top-level OpenAPI v2 definition,
+// e.g. pointerForDefinition("Pet") -> "#/definitions/Pet".
+func pointerForDefinition(name string) string {
+	return "#/definitions/" + name
+}
+
+// pointerForComponentSchema returns the JSON-pointer path of a top-level OpenAPI v3
+// component schema, e.g. pointerForComponentSchema("Pet") -> "#/components/schemas/Pet".
+func pointerForComponentSchema(name string) string {
+	return "#/components/schemas/" + name
+}
+
+// nameForPointer derives a stable Go type name for a synthetic type from the JSON-pointer
+// path at which its schema was found, e.g. "#/definitions/Pet/properties/address" ->
+// "PetAddress". Path segments that only describe the shape of the document, rather than
+// naming anything, are dropped.
+func nameForPointer(pointer string) string {
+	var parts []string
+	for _, segment := range strings.Split(strings.TrimPrefix(pointer, "#/"), "/") {
+		switch segment {
+		case "definitions", "components", "schemas", "properties", "items", "allOf", "additionalProperties":
+			continue
+		}
+		segment = strings.Replace(segment, "~1", "/", -1)
+		if name := sanitizeIdentifier(strings.Title(segment)); name != "" {
+			parts = append(parts, name)
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// sanitizeIdentifier removes characters from s that would be invalid in a generated Go
+// identifier, such as the braces around a path parameter ("{id}").
+func sanitizeIdentifier(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// escapeJSONPointerSegment escapes a single path segment for use in a JSON pointer (RFC
+// 6901): "~" becomes "~0" and "/" becomes "~1". Applying this to a whole path before
+// joining it into a pointer (rather than stripping its slashes and braces outright, as
+// sanitizeIdentifier does for Go identifiers) keeps two distinct paths from colliding on
+// the same pointer.
+func escapeJSONPointerSegment(s string) string {
+	s = strings.Replace(s, "~", "~0", -1)
+	s = strings.Replace(s, "/", "~1", -1)
+	return s
+}
+
+// isRequiredProperty reports whether propertyName is listed in schema's "required" array.
+func isRequiredProperty(schema *openapiv3.Schema, propertyName string) bool {
+	for _, name := range schema.Required {
+		if name == propertyName {
+			return true
+		}
+	}
+	return false
+}
+
+// populateValidationFromSchema copies a property schema's default, enum, and validation
+// constraints onto f, mirroring what the OpenAPI v2 builder reads from a parameter's
+// sub-schema.
+func populateValidationFromSchema(f *Field, schema *openapiv3.Schema) {
+	if schema == nil {
+		return
+	}
+	f.Default = stringForDefault(schema.Default)
+	f.EnumValues = stringsForEnum(schema.Enum)
+	f.Validation = validationForParameter(schema.Pattern, schema.Minimum, schema.Maximum, schema.MinLength, schema.MaxLength)
+}
+
+// positionForParameterLocation maps an OpenAPI v3 parameter's "in" value to the Position
+// a generated field should be serialized at.
+func positionForParameterLocation(in string) Position {
+	switch in {
+	case "path":
+		return Position_PATH
+	case "header":
+		return Position_HEADER
+	case "cookie":
+		return Position_FORMDATA
+	default:
+		return Position_QUERY
+	}
+}
+
+// collectionFormatForStyle maps an OpenAPI v3 parameter's "style" (and "explode") to the
+// OpenAPI v2 "collectionFormat" equivalent a generator can treat the same way.
+func collectionFormatForStyle(style string, explode bool) string {
+	switch style {
+	case "spaceDelimited":
+		return "ssv"
+	case "pipeDelimited":
+		return "pipes"
+	case "form":
+		if explode {
+			return "multi"
+		}
+		return "csv"
+	default:
+		return "csv"
+	}
+}
+
+// mediaUploadExtension is the subset of the "x-google-media-upload"/"x-ms-upload" vendor
+// extension's YAML body that the generated client needs: the resumable upload path and the
+// maximum size of media the server accepts. Shared by the OpenAPI v2 and v3 builders, which
+// each carry the extension's raw YAML in a differently-typed Any wrapper.
+type mediaUploadExtension struct {
+	MaxSize   string `yaml:"maxSize"`
+	Protocols struct {
+		Resumable struct {
+			Path string `yaml:"path"`
+		} `yaml:"resumable"`
+		Simple struct {
+			Path string `yaml:"path"`
+		} `yaml:"simple"`
+	} `yaml:"protocols"`
+}
+
+// parseMediaUploadExtension parses a media-upload vendor extension's raw YAML value and
+// fills in m's MediaUploadPath and MaxUploadSize, preferring the resumable upload protocol's
+// path over the simple one since that is the protocol the generated client implements.
+func parseMediaUploadExtension(m *Method, rawYAML string) {
+	var ext mediaUploadExtension
+	if err := yaml.Unmarshal([]byte(rawYAML), &ext); err != nil {
+		return
+	}
+	if m.MaxUploadSize == "" {
+		m.MaxUploadSize = ext.MaxSize
+	}
+	if m.MediaUploadPath == "" {
+		switch {
+		case ext.Protocols.Resumable.Path != "":
+			m.MediaUploadPath = ext.Protocols.Resumable.Path
+		case ext.Protocols.Simple.Path != "":
+			m.MediaUploadPath = ext.Protocols.Simple.Path
+		}
+	}
+}
+
+// schemaForContent returns the schema of the first media type entry in content, or nil if
+// content has none. Generated clients only need a single representative schema per
+// request/response body, regardless of how many media types are offered for it.
+func schemaForContent(content *openapiv3.MediaTypes) *openapiv3.SchemaOrReference {
+	if content == nil || len(content.AdditionalProperties) == 0 {
+		return nil
+	}
+	mediaType := content.AdditionalProperties[0].Value
+	if mediaType == nil {
+		return nil
+	}
+	return mediaType.Schema
+}
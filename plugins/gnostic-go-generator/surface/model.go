@@ -0,0 +1,123 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gnostic_surface_v1
+
+// Model represents an API service as a set of types and methods. It is built from a
+// source API description document (OpenAPI v2, OpenAPI v3, or a Google API Discovery
+// Document) and is independent of which of those formats was used to describe the API.
+type Model struct {
+	Name    string
+	Types   []*Type
+	Methods []*Method
+}
+
+// Type represents a named data structure used by a Model, such as the body of a request
+// or response, or a named schema definition.
+type Type struct {
+	Name        string
+	Description string
+	Kind        Kind
+	Fields      []*Field
+	MapType     string
+
+	// OneOf lists the alternative Fields of a Kind_UNION Type, one per branch of the
+	// source schema's "oneOf"/"anyOf".
+	OneOf []*Field
+}
+
+// Field represents a single field of a Type.
+type Field struct {
+	Name             string
+	Type             string
+	Position         Position
+	Format           string
+	ValueType        string
+	Serialize        bool
+	Required         bool
+	Default          string
+	EnumValues       []string
+	Repeated         bool
+	CollectionFormat string
+	Validation       *Validation
+}
+
+// Validation carries the numeric, string-length, and pattern constraints declared on a
+// Field's source schema so that generators can produce real input validation.
+type Validation struct {
+	Pattern   string
+	Minimum   float64
+	Maximum   float64
+	MinLength int64
+	MaxLength int64
+}
+
+// Method represents a single API operation, such as GET /users/{id}.
+type Method struct {
+	Operation          string
+	Name               string
+	Path               string
+	Method             string
+	Description        string
+	ParametersTypeName string
+	ResponsesTypeName  string
+
+	SupportsMediaUpload   bool
+	SupportsMediaDownload bool
+	MediaUploadPath       string
+	MaxUploadSize         string
+	AcceptedMediaTypes    []string
+}
+
+// Kind classifies the shape of a Type.
+type Kind int
+
+const (
+	Kind_SCALAR Kind = iota
+	Kind_STRUCT
+	Kind_MAP
+	Kind_ARRAY
+	Kind_ENUM
+	// Kind_UNION marks a Type built from a "oneOf"/"anyOf" schema; its alternatives are
+	// carried in OneOf rather than Fields.
+	Kind_UNION
+	// Kind_REFERENCE marks a Type that is a placeholder for another Type that is still
+	// being resolved, used to break reference cycles during two-pass $ref resolution. A
+	// generator should follow the placeholder's Name to find the real Type once resolution
+	// completes.
+	Kind_REFERENCE
+)
+
+// Position identifies where on the wire a Field's value is carried.
+type Position int
+
+const (
+	Position_BODY Position = iota
+	Position_HEADER
+	Position_FORMDATA
+	Position_QUERY
+	Position_PATH
+)
+
+func (m *Model) addType(t *Type) {
+	m.Types = append(m.Types, t)
+}
+
+func (m *Model) addMethod(method *Method) {
+	m.Methods = append(m.Methods, method)
+}
+
+func (t *Type) addField(f *Field) {
+	t.Fields = append(t.Fields, f)
+}
@@ -0,0 +1,217 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gnostic_surface_v1
+
+import (
+	"fmt"
+
+	"github.com/googleapis/gnostic/discovery"
+)
+
+// NewModelFromDiscovery builds a model of an API service for use in code generation from a
+// Google API Discovery Document.
+func NewModelFromDiscovery(document *discovery.Document) (*Model, error) {
+	return newDiscoveryBuilder().buildModel(document)
+}
+
+// DiscoveryBuilder builds a surface Model from a Discovery Document.
+type DiscoveryBuilder struct {
+	model *Model
+}
+
+func newDiscoveryBuilder() *DiscoveryBuilder {
+	return &DiscoveryBuilder{model: &Model{}}
+}
+
+func (b *DiscoveryBuilder) buildModel(document *discovery.Document) (*Model, error) {
+	// Set model properties from passed-in document.
+	b.model.Name = document.Name
+	b.model.Types = make([]*Type, 0)
+	b.model.Methods = make([]*Method, 0)
+	err := b.build(document)
+	if err != nil {
+		return nil, err
+	}
+	return b.model, nil
+}
+
+// build builds an API service description, preprocessing its types and methods for code generation.
+func (b *DiscoveryBuilder) build(document *discovery.Document) (err error) {
+	// Collect service type descriptions from the schemas section.
+	for name, schema := range document.Schemas {
+		t, err := b.buildTypeFromSchema(name, schema)
+		if err != nil {
+			return err
+		}
+		b.model.addType(t)
+	}
+	// Collect service method descriptions, recursing into nested resources.
+	for name, resource := range document.Resources {
+		b.buildMethodsFromResource(name, resource)
+	}
+	for name, method := range document.Methods {
+		b.buildMethodFromOperation(name, method)
+	}
+	return nil
+}
+
+func (b *DiscoveryBuilder) buildMethodsFromResource(prefix string, resource *discovery.Resource) {
+	for name, method := range resource.Methods {
+		b.buildMethodFromOperation(prefix+"."+name, method)
+	}
+	for name, nested := range resource.Resources {
+		b.buildMethodsFromResource(prefix+"."+name, nested)
+	}
+}
+
+func (b *DiscoveryBuilder) buildTypeFromSchema(name string, schema *discovery.Schema) (t *Type, err error) {
+	t = &Type{}
+	t.Name = name
+	t.Description = "implements the service definition of " + name
+	t.Fields = make([]*Field, 0)
+	if len(schema.Properties) > 0 {
+		// If the schema has properties, generate a struct.
+		t.Kind = Kind_STRUCT
+		for propertyName, propertySchema := range schema.Properties {
+			var f Field
+			f.Name = propertyName
+			f.Type = b.typeForSchema(propertySchema)
+			f.Serialize = true
+			t.addField(&f)
+		}
+	} else if schema.AdditionalProperties != nil {
+		// If the schema has no fixed properties and additional properties of a specified
+		// type, generate a map pointing to objects of that type.
+		t.Kind = Kind_MAP
+		t.MapType = b.typeForSchema(schema.AdditionalProperties)
+	}
+	return t, err
+}
+
+func (b *DiscoveryBuilder) buildMethodFromOperation(operationId string, op *discovery.Method) (err error) {
+	var m Method
+	m.Operation = op.Id
+	m.Path = op.Path
+	m.Method = op.HTTPMethod
+	m.Description = op.Description
+
+	m.Name = sanitizeOperationName(op.Id)
+	if m.Name == "" {
+		// No explicit operationId: fall back to the dotted nested-resource name built up by
+		// buildMethodsFromResource, e.g. "users.messages.list" -> "UsersMessagesList".
+		m.Name = nameForDottedPath(operationId)
+	}
+	if m.Name == "" {
+		m.Name = generateOperationName(op.HTTPMethod, op.Path)
+	}
+
+	m.ParametersTypeName, err = b.buildTypeFromParameters(m.Name, op)
+	m.ResponsesTypeName, err = b.buildTypeFromResponse(m.Name, op.Response)
+	b.model.addMethod(&m)
+	return err
+}
+
+func (b *DiscoveryBuilder) buildTypeFromParameters(name string, op *discovery.Method) (typeName string, err error) {
+	t := &Type{}
+	t.Name = name + "Parameters"
+	t.Description = t.Name + " holds parameters to " + name
+	t.Kind = Kind_STRUCT
+	t.Fields = make([]*Field, 0)
+
+	for parameterName, parameter := range op.Parameters {
+		var f Field
+		f.Name = parameterName
+		f.Type = parameter.Type
+		f.Format = parameter.Format
+		f.Serialize = true
+		switch parameter.Location {
+		case "path":
+			f.Position = Position_PATH
+		case "query":
+			f.Position = Position_QUERY
+		case "header":
+			f.Position = Position_HEADER
+		default:
+			f.Position = Position_QUERY
+		}
+		t.addField(&f)
+	}
+
+	if op.Request != nil && op.Request.Ref != "" {
+		var f Field
+		f.Name = "body"
+		f.Type = typeForRef(op.Request.Ref)
+		f.Position = Position_BODY
+		f.Serialize = true
+		t.addField(&f)
+	}
+
+	if len(t.Fields) > 0 {
+		b.model.addType(t)
+		return t.Name, err
+	}
+	return "", err
+}
+
+func (b *DiscoveryBuilder) buildTypeFromResponse(name string, response *discovery.SchemaRef) (typeName string, err error) {
+	if response == nil || response.Ref == "" {
+		return "", nil
+	}
+	t := &Type{}
+	t.Name = name + "Responses"
+	t.Description = t.Name + " holds responses of " + name
+	t.Kind = Kind_STRUCT
+	t.Fields = make([]*Field, 0)
+
+	var f Field
+	f.Name = "default"
+	f.Serialize = false
+	f.ValueType = typeForRef(response.Ref)
+	f.Type = "*" + f.ValueType
+	t.addField(&f)
+
+	b.model.addType(t)
+	return t.Name, nil
+}
+
+func (b *DiscoveryBuilder) typeForSchema(schema *discovery.Schema) (typeName string) {
+	if schema.Ref != "" {
+		return typeForRef(schema.Ref)
+	}
+	switch schema.Type {
+	case "string":
+		return "string"
+	case "integer":
+		if schema.Format == "int32" {
+			return "int32"
+		}
+		return "int"
+	case "number":
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		if schema.Items != nil {
+			return "[]" + b.typeForSchema(schema.Items)
+		}
+	case "object":
+		if schema.AdditionalProperties != nil {
+			return "map[string]" + b.typeForSchema(schema.AdditionalProperties)
+		}
+		return "map[string]interface{}"
+	}
+	// this function is incomplete... so return a string representing anything that we don't handle
+	return fmt.Sprintf("%v", schema)
+}
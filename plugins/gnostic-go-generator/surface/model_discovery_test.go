@@ -0,0 +1,108 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gnostic_surface_v1
+
+import (
+	"testing"
+
+	"github.com/googleapis/gnostic/discovery"
+)
+
+// TestDiscoveryBuilderGeneratesOperationNames checks the three-step fallback used to name a
+// method when a Discovery Document's method has no explicit "id": first the dotted
+// nested-resource path built up while walking resources, then a name synthesized from the
+// HTTP method and path.
+func TestDiscoveryBuilderGeneratesOperationNames(t *testing.T) {
+	document := &discovery.Document{
+		Name: "test",
+		Resources: map[string]*discovery.Resource{
+			"users": {
+				Methods: map[string]*discovery.Method{
+					"list": {HTTPMethod: "GET", Path: "users"},
+				},
+			},
+		},
+		Methods: map[string]*discovery.Method{
+			"ping": {Id: "myapi.ping", HTTPMethod: "GET", Path: "ping"},
+		},
+	}
+
+	model, err := NewModelFromDiscovery(document)
+	if err != nil {
+		t.Fatalf("NewModelFromDiscovery() error = %v", err)
+	}
+
+	byPath := map[string]string{}
+	for _, m := range model.Methods {
+		byPath[m.Path] = m.Name
+	}
+
+	if got, want := byPath["ping"], "myapi_ping"; got != want {
+		t.Errorf("method with explicit id: Name = %q, want %q", got, want)
+	}
+	if got, want := byPath["users"], "UsersList"; got != want {
+		t.Errorf("method with no id, nested under a resource: Name = %q, want %q", got, want)
+	}
+}
+
+// TestDiscoveryBuilderMapsParameterLocations checks that a Discovery parameter's "location"
+// is mapped to the corresponding surface Position, including the default for a location the
+// builder doesn't otherwise recognize.
+func TestDiscoveryBuilderMapsParameterLocations(t *testing.T) {
+	document := &discovery.Document{
+		Name: "test",
+		Methods: map[string]*discovery.Method{
+			"get": {
+				Id:         "things.get",
+				HTTPMethod: "GET",
+				Path:       "things/{id}",
+				Parameters: map[string]*discovery.Parameter{
+					"id":     {Type: "string", Location: "path"},
+					"filter": {Type: "string", Location: "query"},
+					"token":  {Type: "string", Location: "header"},
+				},
+			},
+		},
+	}
+
+	model, err := NewModelFromDiscovery(document)
+	if err != nil {
+		t.Fatalf("NewModelFromDiscovery() error = %v", err)
+	}
+
+	var params *Type
+	for _, ty := range model.Types {
+		if ty.Name == "things_getParameters" {
+			params = ty
+		}
+	}
+	if params == nil {
+		t.Fatalf("model has no things_getParameters type")
+	}
+
+	byName := map[string]Position{}
+	for _, f := range params.Fields {
+		byName[f.Name] = f.Position
+	}
+	for name, want := range map[string]Position{
+		"id":     Position_PATH,
+		"filter": Position_QUERY,
+		"token":  Position_HEADER,
+	} {
+		if got := byName[name]; got != want {
+			t.Errorf("parameter %q: Position = %v, want %v", name, got, want)
+		}
+	}
+}
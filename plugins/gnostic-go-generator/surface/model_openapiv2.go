@@ -16,6 +16,7 @@ package gnostic_surface_v1
 
 import (
 	"fmt"
+	"strings"
 
 	openapiv2 "github.com/googleapis/gnostic/OpenAPIv2"
 )
@@ -25,12 +26,37 @@ func NewModelFromOpenAPI2(document *openapiv2.Document) (*Model, error) {
 	return newOpenAPI2Builder().buildModel(document)
 }
 
+// OpenAPI2Builder builds a Model from an OpenAPI v2 document in two passes: pass 1
+// registers a placeholder Type for every named definition, and pass 2 fills in each
+// placeholder's Fields, resolving $refs and allOf against the now-complete table. Built
+// this way, a $ref is never rendered as anything less than the type it points to, even
+// when that type is still being resolved (a back-edge).
 type OpenAPI2Builder struct {
 	model *Model
+
+	// definitions holds every named schema from the document's Definitions section, keyed
+	// by name, so that a $ref can be resolved to its schema at any point during pass 2.
+	definitions map[string]*openapiv2.Schema
+
+	// types holds every Type registered so far, named or synthetic, keyed by the
+	// JSON-pointer path of the schema it was built from.
+	types map[string]*Type
+	// order records the registration order of types, so the model's Types are emitted
+	// deterministically.
+	order []string
+	// visiting holds the JSON-pointer paths currently being filled in by fillType, so that
+	// a schema that (directly or through allOf) refers back to itself is recognized as a
+	// back-edge instead of recursed into forever.
+	visiting map[string]bool
 }
 
 func newOpenAPI2Builder() *OpenAPI2Builder {
-	return &OpenAPI2Builder{model: &Model{}}
+	return &OpenAPI2Builder{
+		model:       &Model{},
+		definitions: make(map[string]*openapiv2.Schema),
+		types:       make(map[string]*Type),
+		visiting:    make(map[string]bool),
+	}
 }
 
 func (b *OpenAPI2Builder) buildModel(document *openapiv2.Document) (*Model, error) {
@@ -47,16 +73,24 @@ func (b *OpenAPI2Builder) buildModel(document *openapiv2.Document) (*Model, erro
 
 // buildV2 builds an API service description, preprocessing its types and methods for code generation.
 func (b *OpenAPI2Builder) build(document *openapiv2.Document) (err error) {
-	// Collect service type descriptions from Definitions section.
 	if document.Definitions != nil {
+		// Pass 1: register every named definition as a placeholder Type before resolving
+		// any of them, so that a $ref or an allOf encountered anywhere in pass 2 -- no
+		// matter in what order the definitions happen to appear -- finds something to
+		// point to.
 		for _, pair := range document.Definitions.AdditionalProperties {
-			t, err := b.buildTypeFromDefinition(pair.Name, pair.Value)
-			if err != nil {
-				return err
-			}
-			b.model.addType(t)
+			b.definitions[pair.Name] = pair.Value
+			b.registerPlaceholder(pointerForDefinition(pair.Name), pair.Name)
+		}
+		// Pass 2: fill in each placeholder's Fields and Kind.
+		for _, pair := range document.Definitions.AdditionalProperties {
+			b.fillType(pointerForDefinition(pair.Name), pair.Value)
 		}
 	}
+	for _, pointer := range b.order {
+		b.model.addType(b.types[pointer])
+	}
+
 	// Collect service method descriptions from Paths section.
 	for _, pair := range document.Paths.Path {
 		v := pair.Value
@@ -76,34 +110,114 @@ func (b *OpenAPI2Builder) build(document *openapiv2.Document) (err error) {
 	return err
 }
 
-func (b *OpenAPI2Builder) buildTypeFromDefinition(name string, schema *openapiv2.Schema) (t *Type, err error) {
-	t = &Type{}
-	t.Name = name
-	t.Description = "implements the service definition of " + name
+// registerPlaceholder ensures a Type is registered for pointer, creating one named name
+// with Kind_REFERENCE if it does not already exist. Kind_REFERENCE marks it as not yet
+// filled in; fillType replaces it once it resolves the schema at pointer.
+func (b *OpenAPI2Builder) registerPlaceholder(pointer, name string) *Type {
+	if t, ok := b.types[pointer]; ok {
+		return t
+	}
+	t := &Type{Name: name, Kind: Kind_REFERENCE}
+	b.types[pointer] = t
+	b.order = append(b.order, pointer)
+	return t
+}
+
+// fillType resolves schema into the Type registered at pointer (registering one first if
+// pass 1 did not reach it, as happens for anonymous inline objects), and returns its name.
+// If pointer is already being filled in further up the call stack, this is a back-edge:
+// fillType returns the in-progress placeholder's name without recursing into schema again.
+func (b *OpenAPI2Builder) fillType(pointer string, schema *openapiv2.Schema) string {
+	t := b.types[pointer]
+	if t == nil {
+		t = b.registerPlaceholder(pointer, nameForPointer(pointer))
+	}
+	if schema == nil {
+		// A $ref to a definition that does not exist: leave the placeholder empty rather
+		// than panic on it.
+		return t.Name
+	}
+	if t.Kind != Kind_REFERENCE || b.visiting[pointer] {
+		// Already filled, or a back-edge to a Type still being filled further up the
+		// call stack: either way, it already has the right name to refer to.
+		return t.Name
+	}
+	b.visiting[pointer] = true
+	defer delete(b.visiting, pointer)
+
+	t.Description = "implements the service definition of " + t.Name
 	t.Fields = make([]*Field, 0)
+	t.Kind = Kind_STRUCT
+
+	if len(schema.OneOf) > 0 || len(schema.AnyOf) > 0 {
+		t.Kind = Kind_UNION
+		t.Fields = nil
+		t.OneOf = b.buildUnionFields(pointer, schema.OneOf, schema.AnyOf)
+		return t.Name
+	}
+
+	// Fill in t's own properties before merging its allOf parents' fields, so that a cycle
+	// running back through an allOf (A allOf B, B allOf A) sees this type's own fields
+	// already present when it looks up the in-progress placeholder, instead of an empty
+	// snapshot taken before they were added.
 	if schema.Properties != nil {
-		if len(schema.Properties.AdditionalProperties) > 0 {
-			// If the schema has properties, generate a struct.
-			t.Kind = Kind_STRUCT
-		}
-		for _, pair2 := range schema.Properties.AdditionalProperties {
+		for _, pair := range schema.Properties.AdditionalProperties {
 			var f Field
-			f.Name = pair2.Name
-			f.Type = b.typeForSchema(pair2.Value)
+			f.Name = pair.Name
+			f.Type = b.resolveSchema(pointer+"/properties/"+pair.Name, pair.Value)
 			f.Serialize = true
 			t.addField(&f)
 		}
 	}
-	if len(t.Fields) == 0 {
-		if schema.AdditionalProperties != nil {
-			// If the schema has no fixed properties and additional properties of a specified type,
-			// generate a map pointing to objects of that type.
-			mapType := typeForRef(schema.AdditionalProperties.GetSchema().XRef)
+
+	for i, parent := range schema.AllOf {
+		parentName := b.resolveSchema(fmt.Sprintf("%s/allOf/%d", pointer, i), parent)
+		if parentType := b.typeNamed(parentName); parentType != nil {
+			t.Fields = append(t.Fields, parentType.Fields...)
+		}
+	}
+
+	if len(t.Fields) == 0 && schema.AdditionalProperties != nil {
+		// If the schema has no fixed properties and additional properties of a specified
+		// type, generate a map pointing to objects of that type.
+		if mapSchema := schema.AdditionalProperties.GetSchema(); mapSchema != nil {
 			t.Kind = Kind_MAP
-			t.MapType = mapType
+			t.MapType = b.resolveSchema(pointer+"/additionalProperties", mapSchema)
 		}
 	}
-	return t, err
+	return t.Name
+}
+
+// buildUnionFields resolves each alternative of a oneOf/anyOf schema into a Field, one per
+// branch, named by its position since the source schema gives the branches no names of
+// their own.
+func (b *OpenAPI2Builder) buildUnionFields(pointer string, oneOf, anyOf []*openapiv2.Schema) []*Field {
+	branches := oneOf
+	keyword := "oneOf"
+	if len(branches) == 0 {
+		branches = anyOf
+		keyword = "anyOf"
+	}
+	fields := make([]*Field, 0, len(branches))
+	for i, branch := range branches {
+		var f Field
+		f.Name = fmt.Sprintf("option%d", i)
+		f.Type = b.resolveSchema(fmt.Sprintf("%s/%s/%d", pointer, keyword, i), branch)
+		fields = append(fields, &f)
+	}
+	return fields
+}
+
+// typeNamed returns the first registered Type named name, or nil if none has been
+// registered yet (which, for an allOf parent, means it has not been filled in by the time
+// it was needed -- the back-edge case -- and is merged in as having no fields).
+func (b *OpenAPI2Builder) typeNamed(name string) *Type {
+	for _, pointer := range b.order {
+		if t := b.types[pointer]; t.Name == name {
+			return t
+		}
+	}
+	return nil
 }
 
 func (b *OpenAPI2Builder) buildMethodFromOperation(op *openapiv2.Operation, method string, path string) (err error) {
@@ -118,13 +232,42 @@ func (b *OpenAPI2Builder) buildMethodFromOperation(op *openapiv2.Operation, meth
 		m.Name = generateOperationName(method, path)
 	}
 
-	m.ParametersTypeName, err = b.buildTypeFromParameters(m.Name, op.Parameters)
-	m.ResponsesTypeName, err = b.buildTypeFromResponses(&m, m.Name, op.Responses)
+	pointer := fmt.Sprintf("#/paths/%s/%s", escapeJSONPointerSegment(path), strings.ToLower(method))
+	m.ParametersTypeName, err = b.buildTypeFromParameters(pointer, m.Name, op.Parameters)
+	m.ResponsesTypeName, err = b.buildTypeFromResponses(pointer, m.Name, op.Responses)
+	b.buildMediaSupport(&m, op)
 	b.model.addMethod(&m)
 	return err
 }
 
-func (b *OpenAPI2Builder) buildTypeFromParameters(name string, parameters []*openapiv2.ParametersItem) (typeName string, err error) {
+// buildMediaSupport detects whether an operation accepts an uploaded media body or returns
+// a downloadable media response, from its "consumes"/"produces" media types and from the
+// "x-google-media-upload"/"x-ms-upload" vendor extensions used to describe resumable
+// uploads.
+func (b *OpenAPI2Builder) buildMediaSupport(m *Method, op *openapiv2.Operation) {
+	for _, mediaType := range op.Consumes {
+		if mediaType == "multipart/form-data" || mediaType == "application/octet-stream" {
+			m.SupportsMediaUpload = true
+			m.AcceptedMediaTypes = append(m.AcceptedMediaTypes, mediaType)
+		}
+	}
+	for _, mediaType := range op.Produces {
+		if mediaType == "application/octet-stream" {
+			m.SupportsMediaDownload = true
+		}
+	}
+	for _, extension := range op.VendorExtension {
+		switch extension.Name {
+		case "x-google-media-upload", "x-ms-upload":
+			m.SupportsMediaUpload = true
+			if extension.Value != nil {
+				parseMediaUploadExtension(m, extension.Value.Yaml)
+			}
+		}
+	}
+}
+
+func (b *OpenAPI2Builder) buildTypeFromParameters(pointer string, name string, parameters []*openapiv2.ParametersItem) (typeName string, err error) {
 	t := &Type{}
 	t.Name = name + "Parameters"
 	t.Description = t.Name + " holds parameters to " + name
@@ -132,14 +275,13 @@ func (b *OpenAPI2Builder) buildTypeFromParameters(name string, parameters []*ope
 	t.Fields = make([]*Field, 0)
 	for _, parametersItem := range parameters {
 		var f Field
-		f.Type = fmt.Sprintf("%+v", parametersItem)
 		parameter := parametersItem.GetParameter()
 		if parameter != nil {
 			bodyParameter := parameter.GetBodyParameter()
 			if bodyParameter != nil {
 				f.Name = bodyParameter.Name
 				if bodyParameter.Schema != nil {
-					f.Type = b.typeForSchema(bodyParameter.Schema)
+					f.Type = b.resolveSchema(pointer+"/parameters/"+bodyParameter.Name, bodyParameter.Schema)
 				}
 				f.Position = Position_BODY
 			}
@@ -150,18 +292,51 @@ func (b *OpenAPI2Builder) buildTypeFromParameters(name string, parameters []*ope
 					f.Name = headerParameter.Name
 					f.Type = headerParameter.Type
 					f.Position = Position_HEADER
+					f.Required = headerParameter.Required
+					f.Default = stringForDefault(headerParameter.Default)
+					f.EnumValues = stringsForEnum(headerParameter.Enum)
+					f.Validation = validationForParameter(headerParameter.Pattern, headerParameter.Minimum, headerParameter.Maximum, headerParameter.MinLength, headerParameter.MaxLength)
+					if headerParameter.Type == "array" {
+						f.Repeated = true
+						f.CollectionFormat = headerParameter.CollectionFormat
+						if headerParameter.Items != nil {
+							f.Type = "[]" + headerParameter.Items.Type
+						}
+					}
 				}
 				formDataParameter := nonBodyParameter.GetFormDataParameterSubSchema()
 				if formDataParameter != nil {
 					f.Name = formDataParameter.Name
 					f.Type = formDataParameter.Type
 					f.Position = Position_FORMDATA
+					f.Required = formDataParameter.Required
+					f.Default = stringForDefault(formDataParameter.Default)
+					f.EnumValues = stringsForEnum(formDataParameter.Enum)
+					f.Validation = validationForParameter(formDataParameter.Pattern, formDataParameter.Minimum, formDataParameter.Maximum, formDataParameter.MinLength, formDataParameter.MaxLength)
+					if formDataParameter.Type == "array" {
+						f.Repeated = true
+						f.CollectionFormat = formDataParameter.CollectionFormat
+						if formDataParameter.Items != nil {
+							f.Type = "[]" + formDataParameter.Items.Type
+						}
+					}
 				}
 				queryParameter := nonBodyParameter.GetQueryParameterSubSchema()
 				if queryParameter != nil {
 					f.Name = queryParameter.Name
 					f.Type = queryParameter.Type
 					f.Position = Position_QUERY
+					f.Required = queryParameter.Required
+					f.Default = stringForDefault(queryParameter.Default)
+					f.EnumValues = stringsForEnum(queryParameter.Enum)
+					f.Validation = validationForParameter(queryParameter.Pattern, queryParameter.Minimum, queryParameter.Maximum, queryParameter.MinLength, queryParameter.MaxLength)
+					if queryParameter.Type == "array" {
+						f.Repeated = true
+						f.CollectionFormat = queryParameter.CollectionFormat
+						if queryParameter.Items != nil {
+							f.Type = "[]" + queryParameter.Items.Type
+						}
+					}
 				}
 				pathParameter := nonBodyParameter.GetPathParameterSubSchema()
 				if pathParameter != nil {
@@ -169,8 +344,22 @@ func (b *OpenAPI2Builder) buildTypeFromParameters(name string, parameters []*ope
 					f.Type = pathParameter.Type
 					f.Format = pathParameter.Format
 					f.Position = Position_PATH
+					f.Required = pathParameter.Required
+					f.Default = stringForDefault(pathParameter.Default)
+					f.EnumValues = stringsForEnum(pathParameter.Enum)
+					f.Validation = validationForParameter(pathParameter.Pattern, pathParameter.Minimum, pathParameter.Maximum, pathParameter.MinLength, pathParameter.MaxLength)
+					if pathParameter.Type == "array" {
+						f.Repeated = true
+						f.CollectionFormat = pathParameter.CollectionFormat
+						if pathParameter.Items != nil {
+							f.Type = "[]" + pathParameter.Items.Type
+						}
+					}
 				}
 			}
+			if bodyParameter != nil {
+				f.Required = bodyParameter.Required
+			}
 			f.Serialize = true
 			t.addField(&f)
 		}
@@ -182,7 +371,7 @@ func (b *OpenAPI2Builder) buildTypeFromParameters(name string, parameters []*ope
 	return "", err
 }
 
-func (b *OpenAPI2Builder) buildTypeFromResponses(m *Method, name string, responses *openapiv2.Responses) (typeName string, err error) {
+func (b *OpenAPI2Builder) buildTypeFromResponses(pointer string, name string, responses *openapiv2.Responses) (typeName string, err error) {
 	t := &Type{}
 	t.Name = name + "Responses"
 	t.Description = t.Name + " holds responses of " + name
@@ -195,7 +384,7 @@ func (b *OpenAPI2Builder) buildTypeFromResponses(m *Method, name string, respons
 		f.Serialize = false
 		response := responseCode.Value.GetResponse()
 		if response != nil && response.Schema != nil && response.Schema.GetSchema() != nil {
-			f.ValueType = b.typeForSchema(response.Schema.GetSchema())
+			f.ValueType = b.resolveSchema(pointer+"/responses/"+responseCode.Name, response.Schema.GetSchema())
 			f.Type = "*" + f.ValueType
 			t.addField(&f)
 		}
@@ -208,45 +397,69 @@ func (b *OpenAPI2Builder) buildTypeFromResponses(m *Method, name string, respons
 	return "", err
 }
 
-func (b *OpenAPI2Builder) typeForSchema(schema *openapiv2.Schema) (typeName string) {
-	ref := schema.XRef
-	if ref != "" {
-		return typeForRef(ref)
+// resolveSchema returns the generated Go type name for schema, resolving $refs against the
+// definitions table and registering a synthetic Type -- keyed by pointer -- for any
+// anonymous inline object it encounters. Unlike the single-pass typeForSchema this
+// replaces, it never falls back to dumping the schema itself as a string: a schema shape
+// it does not specifically recognize renders as "interface{}".
+func (b *OpenAPI2Builder) resolveSchema(pointer string, schema *openapiv2.Schema) (typeName string) {
+	if ref := schema.XRef; ref != "" {
+		return b.fillType(ref, b.definitions[typeForRef(ref)])
+	}
+	if len(schema.OneOf) > 0 || len(schema.AnyOf) > 0 {
+		// An anonymous oneOf/anyOf: give it a stable name derived from where it was found
+		// and register it as a Kind_UNION Type, the same way an anonymous object is
+		// registered as a Kind_STRUCT Type.
+		return b.fillType(pointer, schema)
 	}
-	if schema.Type != nil {
-		types := schema.Type.Value
-		format := schema.Format
-		if len(types) == 1 && types[0] == "string" {
+	if schema.Type != nil && len(schema.Type.Value) == 1 {
+		switch schema.Type.Value[0] {
+		case "string":
 			return "string"
-		}
-		if len(types) == 1 && types[0] == "integer" && format == "int32" {
-			return "int32"
-		}
-		if len(types) == 1 && types[0] == "integer" {
-			return "int"
-		}
-		if len(types) == 1 && types[0] == "number" {
+		case "integer":
+			if schema.Format == "int32" {
+				return "int32"
+			}
 			return "int"
-		}
-		if len(types) == 1 && types[0] == "array" && schema.Items != nil {
-			// we have an array.., but of what?
-			items := schema.Items.Schema
-			if len(items) == 1 && items[0].XRef != "" {
-				return "[]" + typeForRef(items[0].XRef)
+		case "number":
+			return "float64"
+		case "boolean":
+			return "bool"
+		case "array":
+			if schema.Items != nil && len(schema.Items.Schema) == 1 {
+				return "[]" + b.resolveSchema(pointer+"/items", schema.Items.Schema[0])
+			}
+			return "[]interface{}"
+		case "object":
+			if mapType, ok := b.resolveAdditionalProperties(pointer, schema); ok {
+				return mapType
+			}
+			if schema.Properties != nil && len(schema.Properties.AdditionalProperties) > 0 {
+				// An anonymous inline object: give it a stable name derived from where it
+				// was found and register it like any other struct Type.
+				return b.fillType(pointer, schema)
 			}
-		}
-		if len(types) == 1 && types[0] == "object" && schema.AdditionalProperties == nil {
 			return "map[string]interface{}"
 		}
 	}
-	if schema.AdditionalProperties != nil {
-		additionalProperties := schema.AdditionalProperties
-		if propertySchema := additionalProperties.GetSchema(); propertySchema != nil {
-			if ref := propertySchema.XRef; ref != "" {
-				return "map[string]" + typeForRef(ref)
-			}
-		}
+	if mapType, ok := b.resolveAdditionalProperties(pointer, schema); ok {
+		return mapType
+	}
+	if schema.Properties != nil && len(schema.Properties.AdditionalProperties) > 0 {
+		return b.fillType(pointer, schema)
+	}
+	return "interface{}"
+}
+
+// resolveAdditionalProperties resolves a schema's "additionalProperties" to a map type, if
+// it declares one.
+func (b *OpenAPI2Builder) resolveAdditionalProperties(pointer string, schema *openapiv2.Schema) (mapType string, ok bool) {
+	if schema.AdditionalProperties == nil {
+		return "", false
+	}
+	propertySchema := schema.AdditionalProperties.GetSchema()
+	if propertySchema == nil {
+		return "", false
 	}
-	// this function is incomplete... so return a string representing anything that we don't handle
-	return fmt.Sprintf("%v", schema)
+	return "map[string]" + b.resolveSchema(pointer+"/additionalProperties", propertySchema), true
 }
@@ -0,0 +1,136 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gnostic_surface_v1
+
+import (
+	"testing"
+
+	openapiv2 "github.com/googleapis/gnostic/OpenAPIv2"
+)
+
+// TestOpenAPI2BuilderBuildsRichParameterFields checks that buildTypeFromParameters carries a
+// parameter's required/default/enum/validation/collectionFormat details onto the generated
+// Field, for both a scalar query parameter and a repeated (array) header parameter.
+func TestOpenAPI2BuilderBuildsRichParameterFields(t *testing.T) {
+	limitParam := &openapiv2.ParametersItem{
+		Oneof: &openapiv2.ParametersItem_Parameter{
+			Parameter: &openapiv2.Parameter{
+				Oneof: &openapiv2.Parameter_NonBodyParameter{
+					NonBodyParameter: &openapiv2.NonBodyParameter{
+						Oneof: &openapiv2.NonBodyParameter_QueryParameterSubSchema{
+							QueryParameterSubSchema: &openapiv2.QueryParameterSubSchema{
+								Name:     "limit",
+								Type:     "integer",
+								Required: true,
+								Default:  10,
+								Enum:     []interface{}{10, 25, 50},
+								Maximum:  100,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	tagsParam := &openapiv2.ParametersItem{
+		Oneof: &openapiv2.ParametersItem_Parameter{
+			Parameter: &openapiv2.Parameter{
+				Oneof: &openapiv2.Parameter_NonBodyParameter{
+					NonBodyParameter: &openapiv2.NonBodyParameter{
+						Oneof: &openapiv2.NonBodyParameter_HeaderParameterSubSchema{
+							HeaderParameterSubSchema: &openapiv2.HeaderParameterSubSchema{
+								Name:             "X-Tags",
+								Type:             "array",
+								CollectionFormat: "multi",
+								Items:            &openapiv2.PrimitivesItems{Type: "string"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	document := &openapiv2.Document{
+		Info: &openapiv2.Info{Title: "params test"},
+		Paths: &openapiv2.Paths{
+			Path: []*openapiv2.NamedPathItem{
+				{
+					Name: "/widgets",
+					Value: &openapiv2.PathItem{
+						Get: &openapiv2.Operation{
+							OperationId: "listWidgets",
+							Parameters:  []*openapiv2.ParametersItem{limitParam, tagsParam},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	model, err := NewModelFromOpenAPI2(document)
+	if err != nil {
+		t.Fatalf("NewModelFromOpenAPI2() error = %v", err)
+	}
+
+	var params *Type
+	for _, ty := range model.Types {
+		if ty.Name == "listWidgetsParameters" {
+			params = ty
+		}
+	}
+	if params == nil {
+		t.Fatalf("model has no listWidgetsParameters type")
+	}
+
+	byName := map[string]*Field{}
+	for _, f := range params.Fields {
+		byName[f.Name] = f
+	}
+
+	limit, ok := byName["limit"]
+	if !ok {
+		t.Fatalf("listWidgetsParameters has no \"limit\" field")
+	}
+	if !limit.Required {
+		t.Errorf("limit.Required = false, want true")
+	}
+	if limit.Default != "10" {
+		t.Errorf("limit.Default = %q, want %q", limit.Default, "10")
+	}
+	if len(limit.EnumValues) != 3 {
+		t.Errorf("limit.EnumValues = %v, want 3 values", limit.EnumValues)
+	}
+	if limit.Validation == nil || limit.Validation.Maximum != 100 {
+		t.Errorf("limit.Validation = %+v, want Maximum = 100", limit.Validation)
+	}
+
+	tags, ok := byName["X-Tags"]
+	if !ok {
+		t.Fatalf("listWidgetsParameters has no \"X-Tags\" field")
+	}
+	if tags.Position != Position_HEADER {
+		t.Errorf("X-Tags.Position = %v, want Position_HEADER", tags.Position)
+	}
+	if !tags.Repeated {
+		t.Errorf("X-Tags.Repeated = false, want true")
+	}
+	if tags.CollectionFormat != "multi" {
+		t.Errorf("X-Tags.CollectionFormat = %q, want %q", tags.CollectionFormat, "multi")
+	}
+	if tags.Type != "[]string" {
+		t.Errorf("X-Tags.Type = %q, want %q", tags.Type, "[]string")
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gnostic_surface_v1
+
+import (
+	"testing"
+
+	openapiv2 "github.com/googleapis/gnostic/OpenAPIv2"
+)
+
+// fieldNames returns the names of ty's fields, for comparing against an expected set.
+func fieldNames(ty *Type) []string {
+	var names []string
+	for _, f := range ty.Fields {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+// hasField reports whether names contains name.
+func hasField(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// TestOpenAPI2BuilderResolvesAllOfCycle builds two definitions that refer to each other
+// through allOf+$ref ("A" allOf "B", "B" allOf "A") and checks that the two-pass builder
+// resolves both without recursing forever, that each ends up fully filled in rather than
+// left as its Kind_REFERENCE placeholder, and that each has both its own field and the
+// field inherited from its allOf parent -- a back-edge through the cycle must not see a
+// snapshot of the in-progress type taken before its own fields were added.
+func TestOpenAPI2BuilderResolvesAllOfCycle(t *testing.T) {
+	schemaA := &openapiv2.Schema{
+		Properties: &openapiv2.Properties{
+			AdditionalProperties: []*openapiv2.NamedSchema{
+				{Name: "a", Value: &openapiv2.Schema{Type: &openapiv2.TypeItem{Value: []string{"string"}}}},
+			},
+		},
+		AllOf: []*openapiv2.Schema{
+			{XRef: "#/definitions/B"},
+		},
+	}
+	schemaB := &openapiv2.Schema{
+		Properties: &openapiv2.Properties{
+			AdditionalProperties: []*openapiv2.NamedSchema{
+				{Name: "b", Value: &openapiv2.Schema{Type: &openapiv2.TypeItem{Value: []string{"string"}}}},
+			},
+		},
+		AllOf: []*openapiv2.Schema{
+			{XRef: "#/definitions/A"},
+		},
+	}
+	document := &openapiv2.Document{
+		Info: &openapiv2.Info{Title: "cycle test"},
+		Definitions: &openapiv2.Definitions{
+			AdditionalProperties: []*openapiv2.NamedSchema{
+				{Name: "A", Value: schemaA},
+				{Name: "B", Value: schemaB},
+			},
+		},
+		Paths: &openapiv2.Paths{},
+	}
+
+	model, err := NewModelFromOpenAPI2(document)
+	if err != nil {
+		t.Fatalf("NewModelFromOpenAPI2() error = %v", err)
+	}
+
+	byName := map[string]*Type{}
+	for _, ty := range model.Types {
+		byName[ty.Name] = ty
+	}
+	for _, name := range []string{"A", "B"} {
+		ty, ok := byName[name]
+		if !ok {
+			t.Fatalf("model has no type named %q", name)
+		}
+		if ty.Kind == Kind_REFERENCE {
+			t.Errorf("type %q was left as an unresolved Kind_REFERENCE placeholder", name)
+		}
+	}
+
+	for _, tc := range []struct {
+		typeName  string
+		wantField string
+	}{
+		{"A", "a"}, {"A", "b"},
+		{"B", "a"}, {"B", "b"},
+	} {
+		names := fieldNames(byName[tc.typeName])
+		if !hasField(names, tc.wantField) {
+			t.Errorf("type %q has fields %v, want it to also include %q (its own field plus its allOf parent's)", tc.typeName, names, tc.wantField)
+		}
+	}
+}
+
+// TestEscapeJSONPointerSegmentKeepsPathsDistinct guards against the bug where two distinct
+// operation paths collapsed onto the same JSON pointer because the pointer was built by
+// stripping slashes and braces instead of escaping them.
+func TestEscapeJSONPointerSegmentKeepsPathsDistinct(t *testing.T) {
+	a := escapeJSONPointerSegment("/a/b")
+	b := escapeJSONPointerSegment("/ab")
+	if a == b {
+		t.Errorf("escapeJSONPointerSegment(%q) == escapeJSONPointerSegment(%q) == %q, want distinct", "/a/b", "/ab", a)
+	}
+}
@@ -0,0 +1,431 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gnostic_surface_v1
+
+import (
+	"fmt"
+	"strings"
+
+	openapiv3 "github.com/googleapis/gnostic/OpenAPIv3"
+)
+
+// NewModelFromOpenAPI3 builds a model of an API service for use in code generation.
+func NewModelFromOpenAPI3(document *openapiv3.Document) (*Model, error) {
+	return newOpenAPI3Builder().buildModel(document)
+}
+
+// OpenAPI3Builder builds a Model from an OpenAPI v3 document in the same two passes as
+// OpenAPI2Builder: pass 1 registers a placeholder Type for every named component schema,
+// and pass 2 fills in each placeholder's Fields, resolving $refs and allOf against the now-
+// complete table. Built this way, a $ref is never rendered as anything less than the type
+// it points to, even when that type is still being resolved (a back-edge).
+type OpenAPI3Builder struct {
+	model *Model
+
+	// schemas holds every named schema from the document's Components.Schemas section, keyed
+	// by name, so that a $ref can be resolved to its schema at any point during pass 2.
+	schemas map[string]*openapiv3.Schema
+
+	// types holds every Type registered so far, named or synthetic, keyed by the
+	// JSON-pointer path of the schema it was built from.
+	types map[string]*Type
+	// order records the registration order of types, so the model's Types are emitted
+	// deterministically.
+	order []string
+	// visiting holds the JSON-pointer paths currently being filled in by fillType, so that
+	// a schema that (directly or through allOf) refers back to itself is recognized as a
+	// back-edge instead of recursed into forever.
+	visiting map[string]bool
+}
+
+func newOpenAPI3Builder() *OpenAPI3Builder {
+	return &OpenAPI3Builder{
+		model:    &Model{},
+		schemas:  make(map[string]*openapiv3.Schema),
+		types:    make(map[string]*Type),
+		visiting: make(map[string]bool),
+	}
+}
+
+func (b *OpenAPI3Builder) buildModel(document *openapiv3.Document) (*Model, error) {
+	// Set model properties from passed-in document.
+	b.model.Name = document.Info.Title
+	b.model.Types = make([]*Type, 0)
+	b.model.Methods = make([]*Method, 0)
+	err := b.build(document)
+	if err != nil {
+		return nil, err
+	}
+	return b.model, nil
+}
+
+// build builds an API service description, preprocessing its types and methods for code generation.
+func (b *OpenAPI3Builder) build(document *openapiv3.Document) (err error) {
+	if document.Components != nil && document.Components.Schemas != nil {
+		// Pass 1: register every named component schema as a placeholder Type before
+		// resolving any of them, so that a $ref or an allOf encountered anywhere in pass 2
+		// -- no matter in what order the schemas happen to appear -- finds something to
+		// point to.
+		for _, pair := range document.Components.Schemas.AdditionalProperties {
+			schema := pair.Value.GetSchema()
+			b.schemas[pair.Name] = schema
+			b.registerPlaceholder(pointerForComponentSchema(pair.Name), pair.Name)
+		}
+		// Pass 2: fill in each placeholder's Fields and Kind.
+		for _, pair := range document.Components.Schemas.AdditionalProperties {
+			b.fillType(pointerForComponentSchema(pair.Name), pair.Value.GetSchema())
+		}
+	}
+	for _, pointer := range b.order {
+		b.model.addType(b.types[pointer])
+	}
+
+	// Collect service method descriptions from the Paths section.
+	for _, pair := range document.Paths.Path {
+		v := pair.Value
+		if v.Get != nil {
+			b.buildMethodFromOperation(v.Get, "GET", pair.Name)
+		}
+		if v.Post != nil {
+			b.buildMethodFromOperation(v.Post, "POST", pair.Name)
+		}
+		if v.Put != nil {
+			b.buildMethodFromOperation(v.Put, "PUT", pair.Name)
+		}
+		if v.Delete != nil {
+			b.buildMethodFromOperation(v.Delete, "DELETE", pair.Name)
+		}
+	}
+	return err
+}
+
+// registerPlaceholder ensures a Type is registered for pointer, creating one named name
+// with Kind_REFERENCE if it does not already exist. Kind_REFERENCE marks it as not yet
+// filled in; fillType replaces it once it resolves the schema at pointer.
+func (b *OpenAPI3Builder) registerPlaceholder(pointer, name string) *Type {
+	if t, ok := b.types[pointer]; ok {
+		return t
+	}
+	t := &Type{Name: name, Kind: Kind_REFERENCE}
+	b.types[pointer] = t
+	b.order = append(b.order, pointer)
+	return t
+}
+
+// fillType resolves schema into the Type registered at pointer (registering one first if
+// pass 1 did not reach it, as happens for anonymous inline objects), and returns its name.
+// If pointer is already being filled in further up the call stack, this is a back-edge:
+// fillType returns the in-progress placeholder's name without recursing into schema again.
+func (b *OpenAPI3Builder) fillType(pointer string, schema *openapiv3.Schema) string {
+	t := b.types[pointer]
+	if t == nil {
+		t = b.registerPlaceholder(pointer, nameForPointer(pointer))
+	}
+	if schema == nil {
+		// A $ref to a schema that does not exist: leave the placeholder empty rather than
+		// panic on it.
+		return t.Name
+	}
+	if t.Kind != Kind_REFERENCE || b.visiting[pointer] {
+		// Already filled, or a back-edge to a Type still being filled further up the call
+		// stack: either way, it already has the right name to refer to.
+		return t.Name
+	}
+	b.visiting[pointer] = true
+	defer delete(b.visiting, pointer)
+
+	t.Description = "implements the service definition of " + t.Name
+	t.Fields = make([]*Field, 0)
+	t.Kind = Kind_STRUCT
+
+	if len(schema.OneOf) > 0 || len(schema.AnyOf) > 0 {
+		t.Kind = Kind_UNION
+		t.Fields = nil
+		t.OneOf = b.buildUnionFields(pointer, schema.OneOf, schema.AnyOf)
+		return t.Name
+	}
+
+	// Fill in t's own properties before merging its allOf parents' fields, so that a cycle
+	// running back through an allOf (A allOf B, B allOf A) sees this type's own fields
+	// already present when it looks up the in-progress placeholder, instead of an empty
+	// snapshot taken before they were added.
+	if schema.Properties != nil {
+		for _, pair := range schema.Properties.AdditionalProperties {
+			var f Field
+			f.Name = pair.Name
+			f.Type = b.resolveSchema(pointer+"/properties/"+pair.Name, pair.Value)
+			f.Serialize = true
+			f.Required = isRequiredProperty(schema, pair.Name)
+			populateValidationFromSchema(&f, pair.Value.GetSchema())
+			t.addField(&f)
+		}
+	}
+
+	for i, parent := range schema.AllOf {
+		parentName := b.resolveSchema(fmt.Sprintf("%s/allOf/%d", pointer, i), parent)
+		if parentType := b.typeNamed(parentName); parentType != nil {
+			t.Fields = append(t.Fields, parentType.Fields...)
+		}
+	}
+
+	if len(t.Fields) == 0 && schema.AdditionalProperties != nil {
+		// If the schema has no fixed properties and additional properties of a specified
+		// type, generate a map pointing to objects of that type.
+		if mapSchema := schema.AdditionalProperties.GetSchema(); mapSchema != nil {
+			t.Kind = Kind_MAP
+			t.MapType = b.resolveSchema(pointer+"/additionalProperties", schema.AdditionalProperties)
+		}
+	}
+	return t.Name
+}
+
+// buildUnionFields resolves each alternative of a oneOf/anyOf schema into a Field, one per
+// branch, named by its position since the source schema gives the branches no names of
+// their own.
+func (b *OpenAPI3Builder) buildUnionFields(pointer string, oneOf, anyOf []*openapiv3.SchemaOrReference) []*Field {
+	branches := oneOf
+	keyword := "oneOf"
+	if len(branches) == 0 {
+		branches = anyOf
+		keyword = "anyOf"
+	}
+	fields := make([]*Field, 0, len(branches))
+	for i, branch := range branches {
+		var f Field
+		f.Name = fmt.Sprintf("option%d", i)
+		f.Type = b.resolveSchema(fmt.Sprintf("%s/%s/%d", pointer, keyword, i), branch)
+		fields = append(fields, &f)
+	}
+	return fields
+}
+
+// typeNamed returns the first registered Type named name, or nil if none has been
+// registered yet (which, for an allOf parent, means it has not been filled in by the time
+// it was needed -- the back-edge case -- and is merged in as having no fields).
+func (b *OpenAPI3Builder) typeNamed(name string) *Type {
+	for _, pointer := range b.order {
+		if t := b.types[pointer]; t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+func (b *OpenAPI3Builder) buildMethodFromOperation(op *openapiv3.Operation, method string, path string) (err error) {
+	var m Method
+	m.Operation = op.OperationId
+	m.Path = path
+	m.Method = method
+	m.Description = op.Description
+
+	m.Name = sanitizeOperationName(op.OperationId)
+	if m.Name == "" {
+		m.Name = generateOperationName(method, path)
+	}
+
+	pointer := fmt.Sprintf("#/paths/%s/%s", escapeJSONPointerSegment(path), strings.ToLower(method))
+	m.ParametersTypeName, err = b.buildTypeFromParameters(pointer, m.Name, op)
+	m.ResponsesTypeName, err = b.buildTypeFromResponses(pointer, m.Name, op.Responses)
+	b.buildMediaSupport(&m, op)
+	b.model.addMethod(&m)
+	return err
+}
+
+// buildMediaSupport detects whether an operation accepts an uploaded media body or returns
+// a downloadable media response, from its request/response content media types and from the
+// "x-google-media-upload"/"x-ms-upload" vendor extensions used to describe resumable
+// uploads -- the OpenAPI v3 equivalent of OpenAPI2Builder.buildMediaSupport.
+func (b *OpenAPI3Builder) buildMediaSupport(m *Method, op *openapiv3.Operation) {
+	if requestBody := op.RequestBody.GetRequestBody(); requestBody != nil && requestBody.Content != nil {
+		for _, pair := range requestBody.Content.AdditionalProperties {
+			if pair.Name == "multipart/form-data" || pair.Name == "application/octet-stream" {
+				m.SupportsMediaUpload = true
+				m.AcceptedMediaTypes = append(m.AcceptedMediaTypes, pair.Name)
+			}
+		}
+	}
+	if op.Responses != nil {
+		for _, pair := range op.Responses.ResponseOrReference {
+			response := pair.Value.GetResponse()
+			if response == nil || response.Content == nil {
+				continue
+			}
+			for _, mediaType := range response.Content.AdditionalProperties {
+				if mediaType.Name == "application/octet-stream" {
+					m.SupportsMediaDownload = true
+				}
+			}
+		}
+	}
+	for _, extension := range op.VendorExtension {
+		switch extension.Name {
+		case "x-google-media-upload", "x-ms-upload":
+			m.SupportsMediaUpload = true
+			if extension.Value != nil {
+				parseMediaUploadExtension(m, extension.Value.Yaml)
+			}
+		}
+	}
+}
+
+func (b *OpenAPI3Builder) buildTypeFromParameters(pointer string, name string, op *openapiv3.Operation) (typeName string, err error) {
+	t := &Type{}
+	t.Name = name + "Parameters"
+	t.Description = t.Name + " holds parameters to " + name
+	t.Kind = Kind_STRUCT
+	t.Fields = make([]*Field, 0)
+
+	for _, parameterOrRef := range op.Parameters {
+		parameter := parameterOrRef.GetParameter()
+		if parameter == nil {
+			continue
+		}
+		var f Field
+		f.Name = parameter.Name
+		f.Required = parameter.Required
+		f.Position = positionForParameterLocation(parameter.In)
+		f.Serialize = true
+		if schema := parameter.Schema.GetSchema(); schema != nil {
+			f.Type = b.resolveSchema(pointer+"/parameters/"+parameter.Name, parameter.Schema)
+			f.Format = schema.Format
+			f.Default = stringForDefault(schema.Default)
+			f.EnumValues = stringsForEnum(schema.Enum)
+			f.Validation = validationForParameter(schema.Pattern, schema.Minimum, schema.Maximum, schema.MinLength, schema.MaxLength)
+			if schema.Type == "array" {
+				f.Repeated = true
+				f.CollectionFormat = collectionFormatForStyle(parameter.Style, parameter.Explode)
+			}
+		}
+		t.addField(&f)
+	}
+
+	if requestBody := op.RequestBody.GetRequestBody(); requestBody != nil {
+		if bodySchema := schemaForContent(requestBody.Content); bodySchema != nil {
+			var f Field
+			f.Name = "body"
+			f.Type = b.resolveSchema(pointer+"/requestBody", bodySchema)
+			f.Position = Position_BODY
+			f.Required = requestBody.Required
+			f.Serialize = true
+			t.addField(&f)
+		}
+	}
+
+	if len(t.Fields) > 0 {
+		b.model.addType(t)
+		return t.Name, err
+	}
+	return "", err
+}
+
+func (b *OpenAPI3Builder) buildTypeFromResponses(pointer string, name string, responses *openapiv3.Responses) (typeName string, err error) {
+	t := &Type{}
+	t.Name = name + "Responses"
+	t.Description = t.Name + " holds responses of " + name
+	t.Kind = Kind_STRUCT
+	t.Fields = make([]*Field, 0)
+
+	if responses != nil {
+		for _, pair := range responses.ResponseOrReference {
+			var f Field
+			f.Name = pair.Name
+			f.Serialize = false
+			response := pair.Value.GetResponse()
+			if response == nil {
+				continue
+			}
+			if bodySchema := schemaForContent(response.Content); bodySchema != nil {
+				f.ValueType = b.resolveSchema(pointer+"/responses/"+pair.Name, bodySchema)
+				f.Type = "*" + f.ValueType
+				t.addField(&f)
+			}
+		}
+	}
+
+	if len(t.Fields) > 0 {
+		b.model.addType(t)
+		return t.Name, err
+	}
+	return "", err
+}
+
+// resolveSchema returns the generated Go type name for schemaOrRef, resolving $refs against
+// the component schemas table and registering a synthetic Type -- keyed by pointer -- for
+// any anonymous inline object it encounters. A schema shape it does not specifically
+// recognize renders as "interface{}" rather than a raw dump of the schema.
+func (b *OpenAPI3Builder) resolveSchema(pointer string, schemaOrRef *openapiv3.SchemaOrReference) (typeName string) {
+	schema := schemaOrRef.GetSchema()
+	if schema == nil {
+		return "interface{}"
+	}
+	if ref := schema.XRef; ref != "" {
+		name := typeForRef(ref)
+		return b.fillType(pointerForComponentSchema(name), b.schemas[name])
+	}
+	if len(schema.OneOf) > 0 || len(schema.AnyOf) > 0 {
+		// An anonymous oneOf/anyOf: give it a stable name derived from where it was found
+		// and register it as a Kind_UNION Type, the same way an anonymous object is
+		// registered as a Kind_STRUCT Type.
+		return b.fillType(pointer, schema)
+	}
+	switch schema.Type {
+	case "string":
+		return "string"
+	case "integer":
+		if schema.Format == "int32" {
+			return "int32"
+		}
+		return "int"
+	case "number":
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		if schema.Items != nil {
+			return "[]" + b.resolveSchema(pointer+"/items", schema.Items)
+		}
+		return "[]interface{}"
+	case "object":
+		if mapType, ok := b.resolveAdditionalProperties(pointer, schema); ok {
+			return mapType
+		}
+		if schema.Properties != nil && len(schema.Properties.AdditionalProperties) > 0 {
+			// An anonymous inline object: give it a stable name derived from where it was
+			// found and register it like any other struct Type.
+			return b.fillType(pointer, schema)
+		}
+		return "map[string]interface{}"
+	}
+	if mapType, ok := b.resolveAdditionalProperties(pointer, schema); ok {
+		return mapType
+	}
+	if schema.Properties != nil && len(schema.Properties.AdditionalProperties) > 0 {
+		return b.fillType(pointer, schema)
+	}
+	return "interface{}"
+}
+
+// resolveAdditionalProperties resolves a schema's "additionalProperties" to a map type, if
+// it declares one.
+func (b *OpenAPI3Builder) resolveAdditionalProperties(pointer string, schema *openapiv3.Schema) (mapType string, ok bool) {
+	if schema.AdditionalProperties == nil {
+		return "", false
+	}
+	propertySchema := schema.AdditionalProperties.GetSchema()
+	if propertySchema == nil {
+		return "", false
+	}
+	return "map[string]" + b.resolveSchema(pointer+"/additionalProperties", schema.AdditionalProperties), true
+}
@@ -0,0 +1,137 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gnostic_surface_v1
+
+import (
+	"testing"
+
+	openapiv3 "github.com/googleapis/gnostic/OpenAPIv3"
+)
+
+func schemaOrRef(schema *openapiv3.Schema) *openapiv3.SchemaOrReference {
+	return &openapiv3.SchemaOrReference{Oneof: &openapiv3.SchemaOrReference_Schema{Schema: schema}}
+}
+
+// TestOpenAPI3BuilderResolvesAllOfCycle is the OpenAPI v3 counterpart to
+// TestOpenAPI2BuilderResolvesAllOfCycle: two component schemas refer to each other through
+// allOf+$ref ("A" allOf "B", "B" allOf "A"), and the two-pass builder must resolve both
+// without recursing forever, with each ending up with both its own field and the one
+// inherited from its allOf parent.
+func TestOpenAPI3BuilderResolvesAllOfCycle(t *testing.T) {
+	schemaA := &openapiv3.Schema{
+		Properties: &openapiv3.Properties{
+			AdditionalProperties: []*openapiv3.NamedSchemaOrReference{
+				{Name: "a", Value: schemaOrRef(&openapiv3.Schema{Type: "string"})},
+			},
+		},
+		AllOf: []*openapiv3.SchemaOrReference{schemaOrRef(&openapiv3.Schema{XRef: "#/components/schemas/B"})},
+	}
+	schemaB := &openapiv3.Schema{
+		Properties: &openapiv3.Properties{
+			AdditionalProperties: []*openapiv3.NamedSchemaOrReference{
+				{Name: "b", Value: schemaOrRef(&openapiv3.Schema{Type: "string"})},
+			},
+		},
+		AllOf: []*openapiv3.SchemaOrReference{schemaOrRef(&openapiv3.Schema{XRef: "#/components/schemas/A"})},
+	}
+	document := &openapiv3.Document{
+		Info: &openapiv3.Info{Title: "cycle test"},
+		Components: &openapiv3.Components{
+			Schemas: &openapiv3.SchemasOrReferences{
+				AdditionalProperties: []*openapiv3.NamedSchemaOrReference{
+					{Name: "A", Value: schemaOrRef(schemaA)},
+					{Name: "B", Value: schemaOrRef(schemaB)},
+				},
+			},
+		},
+		Paths: &openapiv3.Paths{},
+	}
+
+	model, err := NewModelFromOpenAPI3(document)
+	if err != nil {
+		t.Fatalf("NewModelFromOpenAPI3() error = %v", err)
+	}
+
+	byName := map[string]*Type{}
+	for _, ty := range model.Types {
+		byName[ty.Name] = ty
+	}
+	for _, tc := range []struct {
+		typeName  string
+		wantField string
+	}{
+		{"A", "a"}, {"A", "b"},
+		{"B", "a"}, {"B", "b"},
+	} {
+		ty, ok := byName[tc.typeName]
+		if !ok {
+			t.Fatalf("model has no type named %q", tc.typeName)
+		}
+		if ty.Kind == Kind_REFERENCE {
+			t.Errorf("type %q was left as an unresolved Kind_REFERENCE placeholder", tc.typeName)
+		}
+		if !hasField(fieldNames(ty), tc.wantField) {
+			t.Errorf("type %q has fields %v, want it to also include %q", tc.typeName, fieldNames(ty), tc.wantField)
+		}
+	}
+}
+
+// TestOpenAPI3BuilderBuildsUnionFromOneOf checks that a oneOf schema resolves to a
+// Kind_UNION Type whose OneOf fields are named by branch position.
+func TestOpenAPI3BuilderBuildsUnionFromOneOf(t *testing.T) {
+	document := &openapiv3.Document{
+		Info: &openapiv3.Info{Title: "union test"},
+		Components: &openapiv3.Components{
+			Schemas: &openapiv3.SchemasOrReferences{
+				AdditionalProperties: []*openapiv3.NamedSchemaOrReference{
+					{
+						Name: "Pet",
+						Value: schemaOrRef(&openapiv3.Schema{
+							OneOf: []*openapiv3.SchemaOrReference{
+								schemaOrRef(&openapiv3.Schema{Type: "string"}),
+								schemaOrRef(&openapiv3.Schema{Type: "integer"}),
+							},
+						}),
+					},
+				},
+			},
+		},
+		Paths: &openapiv3.Paths{},
+	}
+
+	model, err := NewModelFromOpenAPI3(document)
+	if err != nil {
+		t.Fatalf("NewModelFromOpenAPI3() error = %v", err)
+	}
+
+	var pet *Type
+	for _, ty := range model.Types {
+		if ty.Name == "Pet" {
+			pet = ty
+		}
+	}
+	if pet == nil {
+		t.Fatalf("model has no type named %q", "Pet")
+	}
+	if pet.Kind != Kind_UNION {
+		t.Fatalf("Pet.Kind = %v, want Kind_UNION", pet.Kind)
+	}
+	if len(pet.OneOf) != 2 {
+		t.Fatalf("len(Pet.OneOf) = %d, want 2", len(pet.OneOf))
+	}
+	if pet.OneOf[0].Name != "option0" || pet.OneOf[1].Name != "option1" {
+		t.Errorf("Pet.OneOf names = [%q, %q], want [\"option0\", \"option1\"]", pet.OneOf[0].Name, pet.OneOf[1].Name)
+	}
+}